@@ -2,7 +2,14 @@ package main
 
 import (
 	"V-Woodpecker-V/wsh/warg/flags"
+	"V-Woodpecker-V/wsh/warg/internal/log"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"slices"
+	"strings"
 )
 
 func main() {
@@ -13,47 +20,195 @@ func main() {
 	}
 	addFlag.Children = []*flags.WFlag{
 		{
-			Short:         "s",
-			Long:          "short",
-			Help:          "short version of a flag",
-			Parent:        addFlag,
-			ValueRequired: true,
+			Short: "s",
+			Long:  "short",
+			Help:  "short version of a flag",
+			Type:  flags.StringValue,
 		},
 		{
-			Short:         "l",
-			Long:          "long",
-			Help:          "long version of a flag",
-			Parent:        addFlag,
-			ValueRequired: true,
+			Short: "l",
+			Long:  "long",
+			Help:  "long version of a flag",
+			Type:  flags.StringValue,
 		},
 		{
-			Short:         "h",
-			Long:          "help",
-			Help:          "help message of a flag",
-			Parent:        addFlag,
-			ValueRequired: true,
+			Short: "h",
+			Long:  "help",
+			Help:  "help message of a flag",
+			Type:  flags.StringValue,
 		},
 		{
-			Short:                 "p",
-			Long:                  "parent",
-			Help:                  "which flag to put it under",
-			Parent:                addFlag,
-			NonEmptyValueRequired: true,
+			Short:    "p",
+			Long:     "parent",
+			Help:     "which flag to put it under",
+			Type:     flags.StringValue,
+			NonEmpty: true,
 		},
 		{
-			Short:  "v",
-			Long:   "value",
-			Help:   "this flag requires a value",
-			Parent: addFlag,
+			Short:   "t",
+			Long:    "type",
+			Help:    "type of the flag's value",
+			Type:    flags.EnumValue,
+			Choices: []string{"bool", "string", "int", "uint", "duration", "path", "enum", "count"},
+			Default: "bool",
 		},
 		{
-			Short:  "V",
-			Long:   "non_empty_value",
-			Help:   "this flag requires a value that is not empty",
-			Parent: addFlag,
+			Short: "c",
+			Long:  "choices",
+			Help:  "comma separated values accepted by an enum flag",
+			Type:  flags.StringValue,
 		},
+		{
+			Short: "V",
+			Long:  "non_empty",
+			Help:  "the value must not be empty",
+		},
+		{
+			Short: "R",
+			Long:  "repeatable",
+			Help:  "collect every value of the flag instead of keeping the last one",
+		},
+		{
+			Short: "I",
+			Long:  "inherited",
+			Help:  "the flag does not end a nested context when given inside it",
+		},
+		{
+			Short: "r",
+			Long:  "required",
+			Help:  "the flag must be given with its parent, nested flags only",
+		},
+		{
+			Short: "d",
+			Long:  "default",
+			Help:  "value used when the flag is not given",
+			Type:  flags.StringValue,
+		},
+	}
+	parser := flags.NewParser("warg")
+	parser.AddFlag(addFlag)
+	parser.AddFlag(&flags.WFlag{
+		Short:   "o",
+		Long:    "output",
+		Help:    "format of the parse result",
+		Type:    flags.EnumValue,
+		Choices: []string{"text", "json", "env"},
+		Default: "text",
+	})
+	parser.AddFlag(&flags.WFlag{
+		Long:    "prefix",
+		Help:    "prefix of the variable names in the env output",
+		Type:    flags.StringValue,
+		Default: "WARG_",
+	})
+
+	configPath, err := flags.DefaultConfigPath()
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+	specs, err := flags.LoadFlagSpecs(configPath)
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+	for _, spec := range specs {
+		if err := parser.AddFlagSpec(spec); err != nil {
+			log.Error(fmt.Sprintf("%s: %s", configPath, err))
+			os.Exit(1)
+		}
+	}
+
+	result, err := parser.Parse(os.Args[1:])
+	if errors.Is(err, flags.ErrHelp) || errors.Is(err, flags.ErrComplete) {
+		return
+	}
+	if err != nil {
+		log.Error(err.Error())
+		os.Exit(2)
+	}
+	for _, warning := range result.Warnings {
+		log.Warn(warning)
+	}
+	if result.Bool("add") {
+		if err := addFlagSpec(parser, result, configPath, specs); err != nil {
+			log.Error(err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+	format, prefix := result.String("output"), result.String("prefix")
+	delete(result.Flags, "output")
+	delete(result.Flags, "prefix")
+	if err := printResult(result, format, prefix); err != nil {
+		log.Error(err.Error())
+		os.Exit(1)
+	}
+}
+
+// printResult writes result to stdout. The env format is meant for shell scripts
+// delegating their parsing to warg: eval "$(warg -o env "$@")". Its variables
+// are named after the flags with prefix in front.
+func printResult(result *flags.ParseResult, format, prefix string) error {
+	switch format {
+	case "text":
+		for _, path := range slices.Sorted(maps.Keys(result.Flags)) {
+			fmt.Printf("%s = %v\n", path, result.Flags[path])
+		}
+		for i, arg := range result.Args {
+			fmt.Printf("$%d = %s\n", i+1, arg)
+		}
+	case "json":
+		data, err := result.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "env":
+		env, err := result.Env(prefix)
+		if err != nil {
+			return err
+		}
+		fmt.Print(env)
+	default:
+		return fmt.Errorf("unknown output format: %s", format)
+	}
+	return nil
+}
+
+// addFlagSpec persists the flag described by the -A/--add children and adds it to parser.
+func addFlagSpec(parser *flags.Parser, result *flags.ParseResult, configPath string, specs []flags.FlagSpec) error {
+	valueType, err := flags.ParseValueType(result.String("add.type"))
+	if err != nil {
+		return err
+	}
+	var choices []string
+	if c := result.String("add.choices"); c != "" {
+		choices = strings.Split(c, ",")
+	}
+	spec := flags.FlagSpec{
+		Short:      result.String("add.short"),
+		Long:       result.String("add.long"),
+		Help:       result.String("add.help"),
+		Parent:     result.String("add.parent"),
+		Type:       valueType,
+		NonEmpty:   result.Bool("add.non_empty"),
+		Choices:    choices,
+		Repeatable: result.Bool("add.repeatable"),
+		Inherited:  result.Bool("add.inherited"),
+		Required:   result.Bool("add.required"),
+		Default:    result.String("add.default"),
+	}
+	if err := parser.AddFlagSpec(spec); err != nil {
+		return err
+	}
+	if err := flags.SaveFlagSpecs(configPath, append(specs, spec)); err != nil {
+		return err
+	}
+	path := cmp.Or(spec.Long, spec.Short)
+	if spec.Parent != "" {
+		path = spec.Parent + "." + path
 	}
-	flags.AddFlag(addFlag)
-	flags.ParseArgs(os.Args[1:])
-	flags.DebugPrintFlags()
+	log.Stdout(fmt.Sprintf("added %s to %s", path, configPath))
+	return nil
 }
@@ -2,61 +2,287 @@ package flags
 
 import (
 	"V-Woodpecker-V/wsh/warg/internal/log"
+	"errors"
 	"fmt"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
-func ParseArgs(args []string) error {
-	pArgs := preprocessArgs(args)
+// Parse parses args against the parser's flags. It understands short flag
+// bundles (-abc), short flags with an attached value (-f5), --long and
+// --long=value, and -- ending the flags. A flag taking a value consumes the
+// next argument, even if it starts with a dash. The first other argument may
+// name a command, which parses the rest. Other arguments are collected in
+// ParseResult.Args.
+//
+// If the first argument is the hidden "--complete", Parse prints the
+// completions for the remaining arguments instead and returns ErrComplete.
+func (p *Parser) Parse(args []string) (*ParseResult, error) {
+	if len(args) > 0 && args[0] == "--complete" {
+		for _, c := range p.Complete(args[1:]) {
+			fmt.Fprintln(p.output(), c)
+		}
+		return nil, ErrComplete
+	}
+
+	s := newParseState(p, args)
+	if err := s.parse(); err != nil {
+		return nil, err
+	}
+	return s.result, nil
+}
 
-	var curValueFlag *WFlag
-	curFlagContext := flagRegistry
+// ParseTolerant is like Parse but does not stop at the first problem. It
+// skips unknown flags, missing and invalid values, and missing required
+// flags, and returns everything it could parse along with all the errors.
+// -h/--help and --complete are treated as unknown flags.
+func (p *Parser) ParseTolerant(args []string) (*ParseResult, []error) {
+	s := newParseState(p, args)
+	s.tolerant = true
+	s.parse()
+	for _, err := range s.errs {
+		log.Debug(err.Error())
+	}
+	return s.result, s.errs
+}
 
-	for _, arg := range pArgs {
-		var f *WFlag
-		if strings.HasPrefix(arg, "-") {
-			for f == nil {
-				f = matchFlag(curFlagContext, arg)
-			}
+// ParseLine splits line with Tokenize and parses the resulting arguments.
+func (p *Parser) ParseLine(line string) (*ParseResult, error) {
+	args, err := Tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	return p.Parse(args)
+}
+
+func newParseState(p *Parser, args []string) *parseState {
+	return &parseState{
+		parser: p,
+		args:   args,
+		result: &ParseResult{Flags: map[string]any{}},
+	}
+}
+
+type parseState struct {
+	parser *Parser
+	args   []string
+	pos    int
+	// context is the flag whose children are matched first, nil for the top level.
+	context *WFlag
+	result  *ParseResult
+	// endOfFlags is set once "--" was seen.
+	endOfFlags bool
+	// command is set when run stopped at a subcommand, s.pos is the argument after it.
+	command *Parser
+	// pendingValue is set when the last argument is a flag still waiting for its value.
+	pendingValue *WFlag
+	// tolerant makes fail record errors in errs instead of returning them.
+	tolerant bool
+	errs     []error
+}
+
+// parse runs the parser, applies its defaults and continues with the subcommand, if any.
+func (s *parseState) parse() error {
+	log.Debug(fmt.Sprintf("parsing %q for %s", s.args, s.parser.fullName()))
+	if err := s.run(); err != nil {
+		if errors.Is(err, ErrHelp) {
+			s.parser.Usage()
 		}
-		if f == nil {
-			if curValueFlag == nil || (strings.HasPrefix(arg, "-") && !strings.Contains(arg, " ")) {
-				log.Error(fmt.Sprintf("unknown argument: %s", arg))
-				return fmt.Errorf("unknown argument: %s", arg)
+		return err
+	}
+	if err := s.applyDefaults(s.parser.flags); err != nil {
+		return err
+	}
+	if s.command != nil {
+		return s.runCommand()
+	}
+	return nil
+}
+
+// fail returns err, or records it and returns nil when parsing tolerantly.
+func (s *parseState) fail(err error) error {
+	if err == nil || !s.tolerant {
+		return err
+	}
+	s.errs = append(s.errs, err)
+	return nil
+}
+
+func (s *parseState) run() error {
+	for s.pos < len(s.args) {
+		arg := s.args[s.pos]
+		s.pos++
+		switch {
+		case arg == "--":
+			log.Debug(fmt.Sprintf("end of flags, %d arguments left", len(s.args)-s.pos))
+			s.result.Args = append(s.result.Args, s.args[s.pos:]...)
+			s.pos = len(s.args)
+			s.endOfFlags = true
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg, "=")
+			if err := s.flag(name, value, hasValue, true); err != nil {
+				return err
 			}
-			curValueFlag.setValue(arg)
-		} else {
-			f.setValue(true)
-			if f.ValueRequired || f.NonEmptyValueRequired {
-				curValueFlag = f
+		case len(arg) > 1 && arg[0] == '-':
+			shorts := []rune(arg[1:])
+			for i, short := range shorts {
+				name, rest := "-"+string(short), string(shorts[i+1:])
+				// A value flag takes the rest of the bundle as its value: -f5, -ofile.txt.
+				if f := s.parser.matchInContext(s.context, name); f != nil && f.takesValue() && rest != "" {
+					if err := s.flag(name, rest, true, false); err != nil {
+						return err
+					}
+					break
+				}
+				if err := s.flag(name, "", false, i == len(shorts)-1); err != nil {
+					return err
+				}
 			}
+		default:
+			if cmd := s.parser.findCommand(arg); cmd != nil && len(s.result.Args) == 0 {
+				log.Debug(fmt.Sprintf("entering command %s", cmd.fullName()))
+				s.command = cmd
+				return nil
+			}
+			s.result.Args = append(s.result.Args, arg)
 		}
 	}
 	return nil
 }
 
-func preprocessArgs(args []string) []string {
-	processedArgs := []string{}
-	for _, arg := range args {
-		if !strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "--") {
-			processedArgs = append(processedArgs, strings.Trim(arg, " "))
-		} else {
-			for _, char := range []rune(arg)[1:] {
-				if char == ' ' {
-					continue
+// flag handles the flag written as name. A value flag without an attached
+// value takes the next argument if canTakeNext is set.
+func (s *parseState) flag(name, value string, hasValue, canTakeNext bool) error {
+	f := s.parser.matchInContext(s.context, name)
+	if f == nil {
+		if (name == "-h" || name == "--help") && !s.tolerant {
+			return ErrHelp
+		}
+		err := newParseError(ErrUnknownFlag, name, "unknown flag: %s", name)
+		if err.Suggestions = suggest(name, s.parser.flagNames(s.context)); len(err.Suggestions) > 0 {
+			err.msg += " (did you mean " + strings.Join(err.Suggestions, " or ") + "?)"
+		}
+		return s.fail(err)
+	}
+	log.Debug(fmt.Sprintf("matched %s as %s", name, f.Path()))
+	// A bool flag given as --flag=false does not make its children available.
+	enabled := true
+	if hasValue && f.Type == BoolValue {
+		enabled, _ = strconv.ParseBool(value)
+	}
+	switch {
+	case len(f.Children) > 0 && enabled:
+		s.context = f
+		log.Debug(fmt.Sprintf("entering context %s", f.Path()))
+	case f.Inherited:
+		// Keep the current context, which is f's parent or nested below it.
+	default:
+		s.context = f.Parent
+	}
+	if s.result.Has(s.key(f)) && f.takesValue() && !f.Repeatable {
+		s.result.Warnings = append(s.result.Warnings, fmt.Sprintf("flag %s given more than once, using the last value", name))
+	}
+
+	switch {
+	case hasValue:
+	case f.Type == BoolValue:
+		value = "true"
+	case f.Type == CountValue:
+		value = strconv.FormatInt(s.result.Int(s.key(f))+1, 10)
+	case canTakeNext && s.pos < len(s.args):
+		value = s.args[s.pos]
+		s.pos++
+	default:
+		if canTakeNext {
+			s.pendingValue = f
+		}
+		return s.fail(newParseError(ErrMissingValue, name, "flag %s requires a value", name))
+	}
+	return s.fail(s.setFlag(f, value))
+}
+
+// key returns the ParseResult key of f, its Path prefixed with the path of
+// the command it belongs to and a colon, e.g. "add:verbose" for -v of "tool add".
+// Flag names cannot contain a colon, so the key never matches a flag path.
+func (s *parseState) key(f *WFlag) string {
+	if path := s.parser.commandPath(); len(path) > 0 {
+		return strings.Join(path, " ") + ":" + f.Path()
+	}
+	return f.Path()
+}
+
+// setFlag converts val for f and stores it in the result and in f.Ptr.
+func (s *parseState) setFlag(f *WFlag, val string) error {
+	v, err := f.convertValue(val)
+	if err != nil {
+		return newParseError(ErrInvalidValue, f.displayName(), "%s", err)
+	}
+	if err := f.setValue(v); err != nil {
+		return newParseError(ErrInvalidValue, f.displayName(), "%s", err)
+	}
+	if f.Repeatable {
+		v = appendValue(s.result.Flags[s.key(f)], v)
+	}
+	s.result.Flags[s.key(f)] = v
+	return nil
+}
+
+// appendValue appends v to the slice list, which is nil or a slice of v's type.
+func appendValue(list, v any) any {
+	values := reflect.ValueOf(list)
+	if list == nil {
+		values = reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(v)), 0, 1)
+	}
+	return reflect.Append(values, reflect.ValueOf(v)).Interface()
+}
+
+// applyDefaults sets the Default of missing flags and fails on missing Required ones.
+// Children are only considered when their parent was given, and not as false.
+func (s *parseState) applyDefaults(flags []*WFlag) error {
+	for _, f := range flags {
+		if !s.result.Has(s.key(f)) {
+			if f.Required {
+				err := newParseError(ErrMissingFlag, f.displayName(), "missing required flag %s", f.displayName())
+				if err := s.fail(err); err != nil {
+					return err
+				}
+			}
+			if f.Default != "" {
+				log.Debug(fmt.Sprintf("using default %q for %s", f.Default, f.Path()))
+				if err := s.fail(s.setFlag(f, f.Default)); err != nil {
+					return err
 				}
-				processedArgs = append(processedArgs, fmt.Sprintf("-%c", char))
 			}
+			continue
+		}
+		if s.result.Flags[s.key(f)] == false {
+			continue
+		}
+		if err := s.applyDefaults(f.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchInContext matches arg against the children of context, then against the
+// children of each of its ancestors, so a child can only match once its parent is given.
+func (p *Parser) matchInContext(context *WFlag, arg string) *WFlag {
+	for ; context != nil; context = context.Parent {
+		if f := matchFlag(context.Children, arg); f != nil {
+			return f
 		}
 	}
-	return processedArgs
+	return matchFlag(p.flags, arg)
 }
 
 func matchFlag(flags []*WFlag, arg string) *WFlag {
+	long, isLong := strings.CutPrefix(arg, "--")
+	short := strings.TrimPrefix(arg, "-")
 	for _, wFlag := range flags {
-		a := strings.TrimLeft(arg, "-")
-		if (strings.HasPrefix(arg, "--") && a == wFlag.Long) ||
-			(strings.HasPrefix(arg, "-") && a == wFlag.Short) {
+		if (isLong && long != "" && long == wFlag.Long) ||
+			(!isLong && short != "" && short == wFlag.Short) {
 			return wFlag
 		}
 	}
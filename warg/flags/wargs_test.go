@@ -0,0 +1,491 @@
+package flags
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"slices"
+	"testing"
+)
+
+// newTestParser returns a parser with a top level -s/--silent and a nested
+// -A/--add tree, so -s resolves differently depending on the context:
+//
+//	-s, --silent
+//	-A, --add
+//	  -s, --short <string>
+//	  -B, --nested
+//	    -c, --child
+//	  -O, --other
+func newTestParser() *Parser {
+	p := NewParser("test")
+	p.Output = io.Discard
+	p.AddFlag(&WFlag{Short: "s", Long: "silent"})
+	p.AddFlag(&WFlag{
+		Short: "A",
+		Long:  "add",
+		Children: []*WFlag{
+			{Short: "s", Long: "short", Type: StringValue},
+			{Short: "B", Long: "nested", Children: []*WFlag{
+				{Short: "c", Long: "child"},
+			}},
+			{Short: "O", Long: "other"},
+		},
+	})
+	return p
+}
+
+func TestParseNested(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want map[string]any
+	}{
+		{
+			name: "child matches after its parent",
+			args: []string{"-A", "-s", "x"},
+			want: map[string]any{"add": true, "add.short": "x"},
+		},
+		{
+			name: "same name at top level",
+			args: []string{"-s"},
+			want: map[string]any{"silent": true},
+		},
+		{
+			name: "long child after its parent",
+			args: []string{"--add", "--short=x"},
+			want: map[string]any{"add": true, "add.short": "x"},
+		},
+		{
+			name: "bundle descends into the sub-context",
+			args: []string{"-AB", "-c"},
+			want: map[string]any{"add": true, "add.nested": true, "add.nested.child": true},
+		},
+		{
+			name: "attached value in the sub-context",
+			args: []string{"-Asx"},
+			want: map[string]any{"add": true, "add.short": "x"},
+		},
+		{
+			name: "value flag ending a bundle in the sub-context",
+			args: []string{"-As", "x"},
+			want: map[string]any{"add": true, "add.short": "x"},
+		},
+		{
+			name: "bundle continues in the sub-context",
+			args: []string{"-AO", "-s", "x"},
+			want: map[string]any{"add": true, "add.other": true, "add.short": "x"},
+		},
+		{
+			name: "context pops back after a leaf flag",
+			args: []string{"-A", "-B", "-c", "-s", "x"},
+			want: map[string]any{"add": true, "add.nested": true, "add.nested.child": true, "add.short": "x"},
+		},
+		{
+			name: "top level flag after a nested one",
+			args: []string{"-A", "-O", "--silent"},
+			want: map[string]any{"add": true, "add.other": true, "silent": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newTestParser().Parse(tt.args)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.args, err)
+			}
+			if !maps.Equal(result.Flags, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.args, result.Flags, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseNestedUnknown(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		flag string
+	}{
+		{"child at top level", []string{"-c"}, "-c"},
+		{"long child at top level", []string{"--short", "x"}, "--short"},
+		{"grandchild below its grandparent", []string{"-A", "-c"}, "-c"},
+		{"child in a bundle at top level", []string{"-sO"}, "-O"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestParser().Parse(tt.args)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || !errors.Is(err, ErrUnknownFlag) {
+				t.Fatalf("Parse(%q) error = %v, want an unknown flag error", tt.args, err)
+			}
+			if parseErr.Arg != tt.flag {
+				t.Errorf("Parse(%q) reported %s, want %s", tt.args, parseErr.Arg, tt.flag)
+			}
+		})
+	}
+}
+
+// newFlatParser returns a parser with -v/--verbose, -n/--name <string>, --num <int>,
+// -f/--fd <int>, -o/--out <string> and -c/--count.
+func newFlatParser() *Parser {
+	p := NewParser("test")
+	p.Output = io.Discard
+	p.AddFlags([]*WFlag{
+		{Short: "v", Long: "verbose"},
+		{Short: "n", Long: "name", Type: StringValue},
+		{Long: "num", Type: IntValue},
+		{Short: "f", Long: "fd", Type: IntValue},
+		{Short: "o", Long: "out", Type: StringValue},
+		{Short: "c", Long: "count", Type: CountValue},
+	})
+	return p
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantFlags map[string]any
+		wantArgs  []string
+	}{
+		{
+			name:      "long flag with attached value",
+			args:      []string{"--num=5", "--name=a=b"},
+			wantFlags: map[string]any{"num": int64(5), "name": "a=b"},
+		},
+		{
+			name:      "empty attached value",
+			args:      []string{"--name="},
+			wantFlags: map[string]any{"name": ""},
+		},
+		{
+			name:      "negative number as the next argument",
+			args:      []string{"--num", "-5"},
+			wantFlags: map[string]any{"num": int64(-5)},
+		},
+		{
+			name:      "dash value for a short flag",
+			args:      []string{"-n", "-v", "-v"},
+			wantFlags: map[string]any{"name": "-v", "verbose": true},
+		},
+		{
+			name:      "double dash ends the flags",
+			args:      []string{"-v", "--", "-n", "--num=5", "--"},
+			wantFlags: map[string]any{"verbose": true},
+			wantArgs:  []string{"-n", "--num=5", "--"},
+		},
+		{
+			name:      "double dash as a value",
+			args:      []string{"--name", "--", "x"},
+			wantFlags: map[string]any{"name": "--"},
+			wantArgs:  []string{"x"},
+		},
+		{
+			name:      "attached short value",
+			args:      []string{"-f5", "-n-x"},
+			wantFlags: map[string]any{"fd": int64(5), "name": "-x"},
+		},
+		{
+			name:      "attached negative number",
+			args:      []string{"-f-5"},
+			wantFlags: map[string]any{"fd": int64(-5)},
+		},
+		{
+			name:      "attached value after a bundle",
+			args:      []string{"-ccofile.txt"},
+			wantFlags: map[string]any{"count": int64(2), "out": "file.txt"},
+		},
+		{
+			name:      "attached value naming flags",
+			args:      []string{"-ovc"},
+			wantFlags: map[string]any{"out": "vc"},
+		},
+		{
+			name:      "value flag ending a bundle takes the next argument",
+			args:      []string{"-vo", "file.txt", "a"},
+			wantFlags: map[string]any{"verbose": true, "out": "file.txt"},
+			wantArgs:  []string{"a"},
+		},
+		{
+			name:      "value flag ending a bundle takes a dash argument",
+			args:      []string{"-cf", "-1"},
+			wantFlags: map[string]any{"count": int64(1), "fd": int64(-1)},
+		},
+		{
+			name:      "positional arguments between flags",
+			args:      []string{"a", "-v", "b", "-"},
+			wantFlags: map[string]any{"verbose": true},
+			wantArgs:  []string{"a", "b", "-"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := newFlatParser().Parse(tt.args)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.args, err)
+			}
+			if !maps.Equal(result.Flags, tt.wantFlags) || !slices.Equal(result.Args, tt.wantArgs) {
+				t.Errorf("Parse(%q) = %v %q, want %v %q", tt.args, result.Flags, result.Args, tt.wantFlags, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseArgErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		kind error
+	}{
+		{[]string{"--num"}, ErrMissingValue},
+		{[]string{"-v", "-n"}, ErrMissingValue},
+		{[]string{"-vo"}, ErrMissingValue},
+		{[]string{"-fx"}, ErrInvalidValue},
+		{[]string{"--num=x"}, ErrInvalidValue},
+		{[]string{"--verbose=maybe"}, ErrInvalidValue},
+		{[]string{"--nmu=5"}, ErrUnknownFlag},
+	}
+	for _, tt := range tests {
+		if _, err := newFlatParser().Parse(tt.args); !errors.Is(err, tt.kind) {
+			t.Errorf("Parse(%q) error = %v, want %v", tt.args, err, tt.kind)
+		}
+	}
+}
+
+func TestParseDisabledParent(t *testing.T) {
+	newParser := func() *Parser {
+		p := newTestParser()
+		p.Lookup("add.nested").Children[0].Required = true
+		p.Lookup("add.short").Default = "d"
+		return p
+	}
+	tests := []struct {
+		args []string
+		want map[string]any
+	}{
+		{[]string{"-A"}, map[string]any{"add": true, "add.short": "d"}},
+		{[]string{"--add=false"}, map[string]any{"add": false}},
+		{[]string{"--add=false", "-s"}, map[string]any{"add": false, "silent": true}},
+		{[]string{"--add=true", "-s", "x"}, map[string]any{"add": true, "add.short": "x"}},
+		{[]string{"-A", "--nested=false", "-s", "x"}, map[string]any{"add": true, "add.nested": false, "add.short": "x"}},
+	}
+	for _, tt := range tests {
+		result, err := newParser().Parse(tt.args)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.args, err)
+			continue
+		}
+		if !maps.Equal(result.Flags, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.args, result.Flags, tt.want)
+		}
+	}
+
+	args := []string{"--add=false", "--nested", "-c"}
+	if _, err := newParser().Parse(args); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("Parse(%q) error = %v, want an unknown flag error", args, err)
+	}
+}
+
+func TestParseWarnings(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-n", "a", "--num", "1"}, nil},
+		{[]string{"-v", "-v"}, nil},
+		{[]string{"-n", "a", "--name=b"}, []string{"flag --name given more than once, using the last value"}},
+	}
+	for _, tt := range tests {
+		result, err := newFlatParser().Parse(tt.args)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(result.Warnings, tt.want) {
+			t.Errorf("Parse(%q) warnings = %q, want %q", tt.args, result.Warnings, tt.want)
+		}
+	}
+}
+
+func TestParseTolerant(t *testing.T) {
+	type wantErr struct {
+		kind error
+		arg  string
+	}
+	tests := []struct {
+		name      string
+		args      []string
+		wantFlags map[string]any
+		wantArgs  []string
+		wantErrs  []wantErr
+	}{
+		{
+			name:      "no errors",
+			args:      []string{"--req", "r", "a"},
+			wantFlags: map[string]any{"req": "r"},
+			wantArgs:  []string{"a"},
+		},
+		{
+			name:      "unknown flag and invalid value",
+			args:      []string{"--bogus", "-n", "a", "--num=x", "--req", "r"},
+			wantFlags: map[string]any{"name": "a", "req": "r"},
+			wantErrs:  []wantErr{{ErrUnknownFlag, "--bogus"}, {ErrInvalidValue, "--num"}},
+		},
+		{
+			name:      "missing value and missing required flag",
+			args:      []string{"-v", "--num"},
+			wantFlags: map[string]any{"verbose": true},
+			wantErrs:  []wantErr{{ErrMissingValue, "--num"}, {ErrMissingFlag, "--req"}},
+		},
+		{
+			name:      "help and complete are unknown",
+			args:      []string{"--complete", "-h", "-v", "--req=r", "--help"},
+			wantFlags: map[string]any{"verbose": true, "req": "r"},
+			wantErrs:  []wantErr{{ErrUnknownFlag, "--complete"}, {ErrUnknownFlag, "-h"}, {ErrUnknownFlag, "--help"}},
+		},
+		{
+			name:      "errors in a command",
+			args:      []string{"--req", "r", "sub", "--x", "y", "--bad", "a"},
+			wantFlags: map[string]any{"req": "r"},
+			wantArgs:  []string{"a"},
+			wantErrs:  []wantErr{{ErrInvalidValue, "--x"}, {ErrUnknownFlag, "--bad"}, {ErrMissingFlag, "--x"}},
+		},
+		{
+			name:      "errors before and in a command",
+			args:      []string{"--bogus", "sub", "--x", "1"},
+			wantFlags: map[string]any{"sub:x": int64(1)},
+			wantErrs:  []wantErr{{ErrUnknownFlag, "--bogus"}, {ErrMissingFlag, "--req"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFlatParser()
+			p.AddFlag(&WFlag{Long: "req", Type: StringValue, Required: true})
+			p.AddCommand("sub", "").AddFlag(&WFlag{Long: "x", Type: IntValue, Required: true})
+
+			result, errs := p.ParseTolerant(tt.args)
+			if !maps.Equal(result.Flags, tt.wantFlags) || !slices.Equal(result.Args, tt.wantArgs) {
+				t.Errorf("ParseTolerant(%q) = %v %q, want %v %q", tt.args, result.Flags, result.Args, tt.wantFlags, tt.wantArgs)
+			}
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("ParseTolerant(%q) errors = %v, want %d", tt.args, errs, len(tt.wantErrs))
+			}
+			for i, err := range errs {
+				var parseErr *ParseError
+				if !errors.As(err, &parseErr) || !errors.Is(err, tt.wantErrs[i].kind) || parseErr.Arg != tt.wantErrs[i].arg {
+					t.Errorf("ParseTolerant(%q) error %d = %v, want %v for %s", tt.args, i, err, tt.wantErrs[i].kind, tt.wantErrs[i].arg)
+				}
+			}
+		})
+	}
+}
+
+func TestParseInherited(t *testing.T) {
+	tests := []struct {
+		name      string
+		inherited bool
+		args      []string
+		wantFlags map[string]any
+		wantArgs  []string
+	}{
+		{
+			name:      "top level flag ends the context",
+			args:      []string{"-A", "-q", "-s", "x"},
+			wantFlags: map[string]any{"add": true, "quiet": true, "silent": true},
+			wantArgs:  []string{"x"},
+		},
+		{
+			name:      "inherited flag keeps the context",
+			inherited: true,
+			args:      []string{"-A", "-q", "-s", "x"},
+			wantFlags: map[string]any{"add": true, "quiet": true, "add.short": "x"},
+		},
+		{
+			name:      "inherited flag keeps a nested context",
+			inherited: true,
+			args:      []string{"-AB", "-q", "-c"},
+			wantFlags: map[string]any{"add": true, "add.nested": true, "quiet": true, "add.nested.child": true},
+		},
+		{
+			name:      "inherited flag in a bundle",
+			inherited: true,
+			args:      []string{"-Aqs", "x"},
+			wantFlags: map[string]any{"add": true, "quiet": true, "add.short": "x"},
+		},
+		{
+			name:      "inherited flag at the top level",
+			inherited: true,
+			args:      []string{"-q", "-s"},
+			wantFlags: map[string]any{"quiet": true, "silent": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser()
+			p.AddFlag(&WFlag{Short: "q", Long: "quiet", Inherited: tt.inherited})
+			result, err := p.Parse(tt.args)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.args, err)
+			}
+			if !maps.Equal(result.Flags, tt.wantFlags) || !slices.Equal(result.Args, tt.wantArgs) {
+				t.Errorf("Parse(%q) = %v %q, want %v %q", tt.args, result.Flags, result.Args, tt.wantFlags, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseRepeatable(t *testing.T) {
+	var (
+		from  []string
+		ports []uint16
+	)
+	newParser := func() *Parser {
+		from, ports = nil, nil
+		p := NewParser("test")
+		p.Output = io.Discard
+		p.AddFlags([]*WFlag{
+			{Short: "f", Long: "from", Type: StringValue, Repeatable: true, Ptr: &from},
+			{Short: "p", Long: "port", Repeatable: true, Default: "80", Ptr: &ports},
+			{Long: "tag", Type: StringValue, Repeatable: true},
+		})
+		return p
+	}
+
+	args := []string{"-f", "1", "--from=2", "-f3", "--tag", "a"}
+	result, err := newParser().Parse(args)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", args, err)
+	}
+	if got := Values[string](result, "from"); !slices.Equal(got, []string{"1", "2", "3"}) {
+		t.Errorf("Values(from) = %q, want [1 2 3]", got)
+	}
+	if !slices.Equal(from, []string{"1", "2", "3"}) {
+		t.Errorf("from Ptr = %q, want [1 2 3]", from)
+	}
+	if got := Values[uint64](result, "port"); !slices.Equal(got, []uint64{80}) {
+		t.Errorf("Values(port) = %v, want the default [80]", got)
+	}
+	if !slices.Equal(ports, []uint16{80}) {
+		t.Errorf("port Ptr = %v, want the default [80]", ports)
+	}
+	if got := Values[string](result, "tag"); !slices.Equal(got, []string{"a"}) {
+		t.Errorf("Values(tag) = %q, want [a]", got)
+	}
+	if len(result.Warnings) > 0 {
+		t.Errorf("Parse(%q) warnings = %q, want none", args, result.Warnings)
+	}
+
+	args = []string{"-p", "8080", "-p", "8443"}
+	result, err = newParser().Parse(args)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", args, err)
+	}
+	if got := Values[uint64](result, "port"); !slices.Equal(got, []uint64{8080, 8443}) {
+		t.Errorf("Values(port) = %v, want [8080 8443] without the default", got)
+	}
+	if !slices.Equal(ports, []uint16{8080, 8443}) {
+		t.Errorf("port Ptr = %v, want [8080 8443]", ports)
+	}
+	if result.Has("from") || result.Has("tag") {
+		t.Errorf("Parse(%q) = %v, want no from or tag", args, result.Flags)
+	}
+}
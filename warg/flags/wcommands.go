@@ -0,0 +1,65 @@
+package flags
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AddCommand adds a subcommand with its own flags and subcommands, and returns
+// its parser. The first positional argument naming a command hands the
+// remaining arguments over to it, e.g. "tool add --short s". It panics if
+// name contains a space or a colon, which separate the keys of command flags.
+func (p *Parser) AddCommand(name, help string) *Parser {
+	if name == "" || strings.ContainsAny(name, " :") {
+		panic(fmt.Sprintf("invalid command name: %q", name))
+	}
+	cmd := &Parser{Name: name, Help: help, parent: p}
+	p.commands = append(p.commands, cmd)
+	return cmd
+}
+
+func (p *Parser) findCommand(name string) *Parser {
+	for _, cmd := range p.commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// commandPath returns the names of the commands leading to p, without the root parser's name.
+func (p *Parser) commandPath() []string {
+	if p.parent == nil {
+		return nil
+	}
+	return append(p.parent.commandPath(), p.Name)
+}
+
+// fullName returns the root parser's name followed by the command path, e.g. "tool add".
+func (p *Parser) fullName() string {
+	if p.parent == nil {
+		return p.Name
+	}
+	return p.parent.fullName() + " " + p.Name
+}
+
+// output returns the Output of p or, if unset, of its parent command.
+func (p *Parser) output() io.Writer {
+	if p.Output == nil && p.parent != nil {
+		return p.parent.output()
+	}
+	return p.Output
+}
+
+// runCommand parses the remaining arguments with s.command into the same result,
+// where the keys of its flags are prefixed with its path.
+func (s *parseState) runCommand() error {
+	s.result.Command = strings.Join(s.command.commandPath(), " ")
+	sub := newParseState(s.command, s.args[s.pos:])
+	sub.result = s.result
+	sub.tolerant = s.tolerant
+	err := sub.parse()
+	s.errs = append(s.errs, sub.errs...)
+	return err
+}
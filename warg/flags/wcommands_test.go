@@ -0,0 +1,77 @@
+package flags
+
+import (
+	"errors"
+	"io"
+	"maps"
+	"testing"
+)
+
+// newCommandParser returns a parser with a -v/--verbose count flag and an "add"
+// command with its own required -v/--verbose and a --name defaulting to "x".
+func newCommandParser() *Parser {
+	p := NewParser("tool")
+	p.Output = io.Discard
+	p.AddFlag(&WFlag{Short: "v", Long: "verbose", Type: CountValue})
+	add := p.AddCommand("add", "add something")
+	add.AddFlags([]*WFlag{
+		{Short: "v", Long: "verbose", Required: true},
+		{Long: "name", Type: StringValue, Default: "x"},
+	})
+	return p
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want map[string]any
+	}{
+		{[]string{"-vv"}, map[string]any{"verbose": int64(2)}},
+		{[]string{"add", "-v"}, map[string]any{"add:verbose": true, "add:name": "x"}},
+		{[]string{"-v", "add", "-v"}, map[string]any{"verbose": int64(1), "add:verbose": true, "add:name": "x"}},
+		{[]string{"-vv", "add", "-v", "--name", "y"}, map[string]any{"verbose": int64(2), "add:verbose": true, "add:name": "y"}},
+	}
+	for _, tt := range tests {
+		result, err := newCommandParser().Parse(tt.args)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.args, err)
+			continue
+		}
+		if !maps.Equal(result.Flags, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.args, result.Flags, tt.want)
+		}
+	}
+}
+
+func TestParseCommandRequired(t *testing.T) {
+	for _, args := range [][]string{{"add"}, {"-vv", "add"}} {
+		if _, err := newCommandParser().Parse(args); !errors.Is(err, ErrMissingFlag) {
+			t.Errorf("Parse(%q) error = %v, want a missing flag error", args, err)
+		}
+	}
+}
+
+func TestParseCommandNestedFlagPath(t *testing.T) {
+	// The root --add --name has the path add.name, which must not be mistaken
+	// for the --name of the add command.
+	newParser := func() *Parser {
+		p := NewParser("tool")
+		p.Output = io.Discard
+		p.AddFlag(&WFlag{Long: "add", Children: []*WFlag{{Long: "name", Type: StringValue}}})
+		p.AddCommand("add", "add something").AddFlag(&WFlag{Long: "name", Type: StringValue, Required: true})
+		return p
+	}
+	args := []string{"--add", "--name", "root", "add"}
+	if _, err := newParser().Parse(args); !errors.Is(err, ErrMissingFlag) {
+		t.Errorf("Parse(%q) error = %v, want a missing flag error", args, err)
+	}
+	args = []string{"--add", "--name", "root", "add", "--name", "cmd"}
+	result, err := newParser().Parse(args)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", args, err)
+	}
+	want := map[string]any{"add": true, "add.name": "root", "add:name": "cmd"}
+	if !maps.Equal(result.Flags, want) {
+		t.Errorf("Parse(%q) = %v, want %v", args, result.Flags, want)
+	}
+}
@@ -0,0 +1,96 @@
+package flags
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrComplete is returned by Parse after printing completions for --complete.
+var ErrComplete = errors.New("flags: completion requested")
+
+// Complete returns the flags that can complete the last of words, given the
+// words before it. The last word may be empty to complete a new argument.
+// Where a positional argument is expected, the matching subcommands are
+// returned. Where a flag value is expected, only the Choices of an EnumValue
+// flag are returned. Unknown flags and bad values in the earlier words are
+// skipped.
+//
+// A zsh completion function can use it as
+//
+//	compadd -- $(tool --complete "${words[@]:1:CURRENT-1}")
+func (p *Parser) Complete(words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	prefix := words[len(words)-1]
+
+	s := newParseState(p, words[:len(words)-1])
+	s.tolerant = true
+	s.run()
+	if s.pendingValue != nil {
+		var choices []string
+		for _, choice := range s.pendingValue.Choices {
+			if strings.HasPrefix(choice, prefix) {
+				choices = append(choices, choice)
+			}
+		}
+		return choices
+	}
+	if s.endOfFlags {
+		return nil
+	}
+	if s.command != nil {
+		return s.command.Complete(words[s.pos:])
+	}
+
+	var candidates []string
+	if !strings.HasPrefix(prefix, "-") {
+		if len(s.result.Args) > 0 {
+			return nil
+		}
+		for _, cmd := range p.commands {
+			if strings.HasPrefix(cmd.Name, prefix) {
+				candidates = append(candidates, cmd.Name)
+			}
+		}
+		if prefix != "" {
+			return candidates
+		}
+	}
+	for _, name := range p.flagNames(s.context) {
+		if strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// flagNames returns the flag names that can be matched in context, those of
+// its children first, then those of its ancestors, then -h/--help unless a
+// flag uses them.
+func (p *Parser) flagNames(context *WFlag) []string {
+	var names []string
+	for c := context; ; c = c.Parent {
+		flags := p.flags
+		if c != nil {
+			flags = c.Children
+		}
+		for _, f := range flags {
+			// A name shadowed by a flag closer to the context resolves to that flag instead.
+			for _, name := range []string{"--" + f.Long, "-" + f.Short} {
+				if name != "--" && name != "-" && p.matchInContext(context, name) == f {
+					names = append(names, name)
+				}
+			}
+		}
+		if c == nil {
+			break
+		}
+	}
+	for _, help := range []string{"--help", "-h"} {
+		if p.matchInContext(context, help) == nil {
+			names = append(names, help)
+		}
+	}
+	return names
+}
@@ -0,0 +1,130 @@
+package flags
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// newCompleteParser returns newTestParser with an -o/--output enum flag and
+// two commands, "sub" having its own -h:
+//
+//	sub
+//	  -h, --host
+//	  --x
+//	status
+func newCompleteParser() *Parser {
+	p := newTestParser()
+	p.AddFlag(&WFlag{Short: "o", Long: "output", Type: EnumValue, Choices: []string{"text", "json", "env"}})
+	p.AddCommand("sub", "").AddFlags([]*WFlag{
+		{Short: "h", Long: "host"},
+		{Long: "x"},
+	})
+	p.AddCommand("status", "")
+	return p
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{
+			name:  "empty",
+			words: []string{""},
+			want:  []string{"sub", "status", "--silent", "-s", "--add", "-A", "--output", "-o", "--help", "-h"},
+		},
+		{
+			name:  "no words",
+			words: nil,
+			want:  []string{"sub", "status", "--silent", "-s", "--add", "-A", "--output", "-o", "--help", "-h"},
+		},
+		{
+			name:  "command prefix",
+			words: []string{"s"},
+			want:  []string{"sub", "status"},
+		},
+		{
+			name:  "flag prefix",
+			words: []string{"--a"},
+			want:  []string{"--add"},
+		},
+		{
+			name:  "children first, shadowed -s left out",
+			words: []string{"-A", "-"},
+			want:  []string{"--short", "-s", "--nested", "-B", "--other", "-O", "--silent", "--add", "-A", "--output", "-o", "--help", "-h"},
+		},
+		{
+			name:  "child and top level long names",
+			words: []string{"-A", "--s"},
+			want:  []string{"--short", "--silent"},
+		},
+		{
+			name:  "grandchildren",
+			words: []string{"-AB", "--c"},
+			want:  []string{"--child"},
+		},
+		{
+			name:  "commands after a flag value",
+			words: []string{"-A", "-s", "x", "s"},
+			want:  []string{"sub", "status"},
+		},
+		{
+			name:  "enum values",
+			words: []string{"-o", ""},
+			want:  []string{"text", "json", "env"},
+		},
+		{
+			name:  "enum value prefix",
+			words: []string{"-A", "--output", "e"},
+			want:  []string{"env"},
+		},
+		{
+			name:  "no choices for a string value",
+			words: []string{"-A", "-s", ""},
+			want:  nil,
+		},
+		{
+			name:  "no completion after --",
+			words: []string{"-A", "--", "-"},
+			want:  nil,
+		},
+		{
+			name:  "no completion after a positional argument",
+			words: []string{"a", ""},
+			want:  nil,
+		},
+		{
+			name:  "command flags, -h taken by --host",
+			words: []string{"-s", "sub", "-"},
+			want:  []string{"--host", "-h", "--x", "--help"},
+		},
+		{
+			name:  "unknown flags are skipped",
+			words: []string{"--bogus", "-A", "--o"},
+			want:  []string{"--other", "--output"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newCompleteParser().Complete(tt.words); !slices.Equal(got, tt.want) {
+				t.Errorf("Complete(%q) = %q, want %q", tt.words, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseComplete(t *testing.T) {
+	var out bytes.Buffer
+	p := newCompleteParser()
+	p.Output = &out
+	result, err := p.Parse([]string{"--complete", "sub", "--"})
+	if !errors.Is(err, ErrComplete) || result != nil {
+		t.Fatalf("Parse returned %v, %v, want ErrComplete", result, err)
+	}
+	if want := "--host\n--x\n--help\n"; out.String() != want {
+		t.Errorf("Parse printed %q, want %q", out.String(), want)
+	}
+}
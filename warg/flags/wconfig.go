@@ -0,0 +1,154 @@
+package flags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FlagSpec is the persisted form of a WFlag. Parent is the Path of the flag it is nested under.
+type FlagSpec struct {
+	Short      string    `json:"short,omitempty"`
+	Long       string    `json:"long,omitempty"`
+	Help       string    `json:"help,omitempty"`
+	Parent     string    `json:"parent,omitempty"`
+	Type       ValueType `json:"type,omitempty"`
+	NonEmpty   bool      `json:"non_empty,omitempty"`
+	Choices    []string  `json:"choices,omitempty"`
+	Repeatable bool      `json:"repeatable,omitempty"`
+	Inherited  bool      `json:"inherited,omitempty"`
+	Required   bool      `json:"required,omitempty"`
+	Default    string    `json:"default,omitempty"`
+}
+
+// DefaultConfigPath returns $WARG_CONFIG, or flags.json in the user's warg config directory.
+func DefaultConfigPath() (string, error) {
+	if path := os.Getenv("WARG_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "warg", "flags.json"), nil
+}
+
+// LoadFlagSpecs reads the specs stored at path. A missing file yields no specs.
+func LoadFlagSpecs(path string) ([]FlagSpec, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var specs []FlagSpec
+	if err := json.Unmarshal(data, &specs); err != nil {
+		return nil, fmt.Errorf("invalid flag config %s: %w", path, err)
+	}
+	return specs, nil
+}
+
+func SaveFlagSpecs(path string, specs []FlagSpec) error {
+	data, err := json.MarshalIndent(specs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// AddFlagSpec builds a WFlag from spec and adds it to the parser, nested under spec.Parent if set.
+// The parent must itself have been added by AddFlagSpec.
+func (p *Parser) AddFlagSpec(spec FlagSpec) error {
+	if spec.Short == "" && spec.Long == "" {
+		return fmt.Errorf("flag needs a short or long name")
+	}
+	if strings.ContainsAny(spec.Short+spec.Long, " =.") || strings.HasPrefix(spec.Short, "-") || strings.HasPrefix(spec.Long, "-") {
+		return fmt.Errorf("invalid flag name: -%s --%s", spec.Short, spec.Long)
+	}
+	if len([]rune(spec.Short)) > 1 {
+		return fmt.Errorf("short flag must be a single character: %s", spec.Short)
+	}
+	// -h/--help and --complete are handled by Parse unless a flag takes them over.
+	if spec.Parent == "" && (spec.Short == "h" || spec.Long == "help" || spec.Long == "complete") {
+		return fmt.Errorf("-h, --help and --complete are reserved at the top level")
+	}
+
+	flag := &WFlag{
+		Short:      spec.Short,
+		Long:       spec.Long,
+		Help:       spec.Help,
+		Type:       spec.Type,
+		NonEmpty:   spec.NonEmpty,
+		Choices:    spec.Choices,
+		Repeatable: spec.Repeatable,
+		Inherited:  spec.Inherited,
+		Required:   spec.Required,
+		Default:    spec.Default,
+		fromSpec:   true,
+	}
+	// A required top level flag would make every run without it fail, including warg -A.
+	if flag.Required && spec.Parent == "" {
+		return fmt.Errorf("only nested flags can be required: %s", flag.displayName())
+	}
+	if err := flag.validate(); err != nil {
+		return err
+	}
+	if flag.Default != "" {
+		if _, err := flag.convertValue(flag.Default); err != nil {
+			return fmt.Errorf("invalid default: %w", err)
+		}
+	}
+	siblings := p.flags
+	if spec.Parent != "" {
+		parent := p.Lookup(spec.Parent)
+		if parent == nil {
+			return fmt.Errorf("unknown parent flag: %s", spec.Parent)
+		}
+		// Specs only extend other specs. A required child of a built-in flag such
+		// as warg's -A would make every later warg -A fail.
+		if !parent.fromSpec {
+			return fmt.Errorf("cannot add flags under the built-in flag %s", parent.displayName())
+		}
+		flag.Parent = parent
+		siblings = parent.Children
+	}
+	for _, s := range siblings {
+		if (flag.Short != "" && s.Short == flag.Short) || (flag.Long != "" && s.Long == flag.Long) {
+			return fmt.Errorf("flag -%s --%s conflicts with -%s --%s", flag.Short, flag.Long, s.Short, s.Long)
+		}
+	}
+
+	if flag.Parent != nil {
+		flag.Parent.Children = append(flag.Parent.Children, flag)
+	} else {
+		p.AddFlag(flag)
+	}
+	return nil
+}
+
+// Lookup returns the flag with the given Path, or nil.
+func (p *Parser) Lookup(path string) *WFlag {
+	context := p.flags
+	var found *WFlag
+	for name := range strings.SplitSeq(path, ".") {
+		found = nil
+		for _, f := range context {
+			if f.Name() == name {
+				found = f
+				break
+			}
+		}
+		if found == nil {
+			return nil
+		}
+		context = found.Children
+	}
+	return found
+}
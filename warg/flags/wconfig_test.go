@@ -0,0 +1,182 @@
+package flags
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAddFlagSpecInvalid(t *testing.T) {
+	for _, spec := range []FlagSpec{
+		{Long: "cr", Type: CountValue, Repeatable: true},
+		{Long: "n", Type: IntValue, NonEmpty: true},
+		{Long: "p", Type: PathValue, NonEmpty: true},
+		{Long: "e", Type: EnumValue},
+		{Long: "a:b"},
+	} {
+		if err := NewParser("test").AddFlagSpec(spec); err == nil {
+			t.Errorf("AddFlagSpec(%+v) succeeded, want an error", spec)
+		}
+	}
+}
+
+// newBuiltinParser returns a parser with a built-in -A/--add flag like warg's.
+func newBuiltinParser() *Parser {
+	p := NewParser("test")
+	p.Output = io.Discard
+	p.AddFlag(&WFlag{Short: "A", Long: "add", Children: []*WFlag{
+		{Short: "l", Long: "long", Type: StringValue},
+	}})
+	return p
+}
+
+func TestAddFlagSpecUnderBuiltin(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.json")
+	specs := []FlagSpec{
+		{Long: "grp"},
+		{Long: "token", Parent: "grp", Type: StringValue, Required: true},
+		{Long: "sub", Parent: "add", Required: true},
+	}
+	if err := SaveFlagSpecs(path, specs); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFlagSpecs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newBuiltinParser()
+	for _, spec := range loaded[:2] {
+		if err := p.AddFlagSpec(spec); err != nil {
+			t.Fatalf("AddFlagSpec(%+v) failed: %v", spec, err)
+		}
+	}
+	if err := p.AddFlagSpec(loaded[2]); err == nil {
+		t.Errorf("AddFlagSpec(%+v) succeeded, want an error", loaded[2])
+	}
+
+	// The required child of a persisted flag does not get in the way of -A.
+	args := []string{"-A", "--long", "other"}
+	if _, err := p.Parse(args); err != nil {
+		t.Errorf("Parse(%q) failed: %v", args, err)
+	}
+}
+
+func TestFlagSpecRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warg", "flags.json")
+	specs := []FlagSpec{
+		{Short: "r", Long: "remote", Help: "remote settings"},
+		{Short: "u", Long: "url", Parent: "remote", Type: StringValue, NonEmpty: true, Required: true},
+		{Long: "mode", Parent: "remote", Type: EnumValue, Choices: []string{"push", "fetch"}, Default: "fetch"},
+		{Short: "t", Long: "timeout", Type: DurationValue},
+		{Short: "I", Long: "include", Type: PathValue, Repeatable: true, Inherited: true},
+	}
+	if err := SaveFlagSpecs(path, specs); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFlagSpecs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, specs) {
+		t.Fatalf("LoadFlagSpecs = %+v, want %+v", loaded, specs)
+	}
+
+	p := NewParser("test")
+	p.Output = io.Discard
+	for _, spec := range loaded {
+		if err := p.AddFlagSpec(spec); err != nil {
+			t.Fatalf("AddFlagSpec(%+v) failed: %v", spec, err)
+		}
+	}
+	if f := p.Lookup("remote.url"); f == nil || f.Parent != p.Lookup("remote") || !f.Required {
+		t.Errorf("Lookup(remote.url) = %+v, want the required child of remote", f)
+	}
+	for _, path := range []string{"url", "remote.timeout", "remote.url.x", ""} {
+		if f := p.Lookup(path); f != nil {
+			t.Errorf("Lookup(%q) = %+v, want nil", path, f)
+		}
+	}
+
+	args := []string{"-t", "90s", "-r", "-u", "git@host", "-I", "/a/../b"}
+	result, err := p.Parse(args)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", args, err)
+	}
+	want := map[string]any{
+		"timeout":     90 * time.Second,
+		"remote":      true,
+		"remote.url":  "git@host",
+		"remote.mode": "fetch",
+		"include":     []string{"/b"},
+	}
+	if !reflect.DeepEqual(result.Flags, want) {
+		t.Errorf("Parse(%q) = %v, want %v", args, result.Flags, want)
+	}
+	args = []string{"-r"}
+	if _, err := p.Parse(args); !errors.Is(err, ErrMissingFlag) {
+		t.Errorf("Parse(%q) error = %v, want a missing flag error", args, err)
+	}
+}
+
+func TestLoadFlagSpecs(t *testing.T) {
+	dir := t.TempDir()
+	if specs, err := LoadFlagSpecs(filepath.Join(dir, "missing.json")); specs != nil || err != nil {
+		t.Errorf("LoadFlagSpecs of a missing file = %v, %v, want no specs", specs, err)
+	}
+	path := filepath.Join(dir, "flags.json")
+	for _, data := range []string{"{", `[{"long": "x", "type": "float"}]`} {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadFlagSpecs(path); err == nil {
+			t.Errorf("LoadFlagSpecs(%s) succeeded, want an error", data)
+		}
+	}
+
+	t.Setenv("WARG_CONFIG", path)
+	if got, err := DefaultConfigPath(); got != path || err != nil {
+		t.Errorf("DefaultConfigPath() = %q, %v, want %q", got, err, path)
+	}
+}
+
+func TestAddFlagSpecConflicts(t *testing.T) {
+	p := NewParser("test")
+	for _, spec := range []FlagSpec{
+		{Short: "r", Long: "remote"},
+		{Short: "u", Long: "url", Parent: "remote"},
+		// The same names are fine in another context, and -h/--help only
+		// matter at the top level.
+		{Short: "r", Long: "recursive", Parent: "remote"},
+		{Short: "h", Long: "help", Parent: "remote"},
+	} {
+		if err := p.AddFlagSpec(spec); err != nil {
+			t.Fatalf("AddFlagSpec(%+v) failed: %v", spec, err)
+		}
+	}
+	for _, spec := range []FlagSpec{
+		{},
+		{Short: "r", Long: "other"},
+		{Long: "remote"},
+		{Short: "u", Parent: "remote"},
+		{Long: "url", Parent: "remote"},
+		{Long: "x", Parent: "nope"},
+		{Long: "x", Parent: "remote.nope"},
+		{Short: "h"},
+		{Long: "help"},
+		{Long: "complete"},
+		{Short: "ab"},
+		{Long: "a.b"},
+		{Long: "a=b"},
+		{Long: "-x"},
+		{Long: "top", Required: true},
+		{Long: "x", Type: IntValue, Default: "y"},
+	} {
+		if err := p.AddFlagSpec(spec); err == nil {
+			t.Errorf("AddFlagSpec(%+v) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of ParseError, usable with errors.Is.
+var (
+	ErrSyntax       = errors.New("syntax error")
+	ErrUnknownFlag  = errors.New("unknown flag")
+	ErrMissingValue = errors.New("missing value")
+	ErrInvalidValue = errors.New("invalid value")
+	ErrMissingFlag  = errors.New("missing required flag")
+)
+
+// ParseError is returned by Parse and Tokenize. Kind is one of the Err values above
+// and Arg the argument or flag it is about.
+type ParseError struct {
+	Kind error
+	Arg  string
+	// Suggestions holds the known flags close to an unknown one, closest first.
+	Suggestions []string
+	msg         string
+}
+
+func newParseError(kind error, arg string, format string, a ...any) *ParseError {
+	return &ParseError{Kind: kind, Arg: arg, msg: fmt.Sprintf(format, a...)}
+}
+
+func (e *ParseError) Error() string {
+	return e.msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Kind
+}
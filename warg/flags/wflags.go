@@ -1,100 +1,241 @@
 package flags
 
 import (
+	"V-Woodpecker-V/wsh/warg/internal/log"
 	"fmt"
+	"io"
+	"os"
 	"reflect"
-	"strconv"
+	"strings"
+	"time"
 )
 
-var flagRegistry []*WFlag
-
 type WFlag struct {
-	Short                 string
-	Long                  string
-	Help                  string
-	Parent                *WFlag
-	Children              []*WFlag
-	ValueRequired         bool
-	NonEmptyValueRequired bool
-	ptr                   any
-}
-
-func AddFlag(flag *WFlag) {
-	v := reflect.ValueOf(flag.ptr)
-	if v.Kind() != reflect.Pointer {
-		panic("flag.ptr must be a pointer")
-	}
+	Short    string
+	Long     string
+	Help     string
+	Parent   *WFlag
+	Children []*WFlag
+	// Type is the value the flag takes. It is inferred from Ptr when left as BoolValue.
+	Type ValueType
+	// NonEmpty rejects an empty value for StringValue flags.
+	NonEmpty bool
+	// Choices lists the values accepted by an EnumValue flag.
+	Choices []string
+	// Repeatable collects every value of the flag in a slice instead of keeping the last one.
+	Repeatable bool
+	// Inherited keeps the current context when the flag is given below its parent's context,
+	// so -A -q -s x still matches -s among the children of -A even though -q is a top level flag.
+	Inherited bool
+	// Required makes Parse fail when the flag is missing while its parent is given.
+	Required bool
+	// Default is used when the flag is missing while its parent is given.
+	Default string
+	// Ptr optionally binds the flag to a variable that is set while parsing.
+	Ptr any
+	// fromSpec marks flags added by AddFlagSpec.
+	fromSpec bool
+}
 
-	flagRegistry = append(flagRegistry, flag)
+// Parser holds a tree of flags and parses command lines against it.
+type Parser struct {
+	Name string
+	// Help describes a command in its parent's usage.
+	Help string
+	// Output receives the usage text and completions, os.Stdout by default.
+	// Commands without an Output use their parent's.
+	Output   io.Writer
+	flags    []*WFlag
+	commands []*Parser
+	parent   *Parser
 }
 
-func AddFlags(flags []*WFlag) {
-	for _, flag := range flags {
-		AddFlag(flag)
-	}
+// ParseResult holds the flags seen by Parse and the defaults of missing ones, keyed by WFlag.Path.
+// The keys of a command's flags are prefixed with the command path and a colon, e.g. "add:verbose".
+// Values are converted according to the flag's Type: bool, string (also for
+// PathValue and EnumValue), int64, uint64 or time.Duration. Repeatable flags
+// hold a slice of those, see Values.
+type ParseResult struct {
+	Flags map[string]any
+	// Args holds the arguments that are not flags or flag values, and everything after "--".
+	Args []string
+	// Warnings holds problems that did not make parsing fail, such as a flag given twice.
+	Warnings []string
+	// Command is the space separated path of the subcommand that was run, empty for none.
+	// Its flags are stored in Flags under the command path and a colon, e.g. "remote add:verbose"
+	// for "tool remote add --verbose".
+	Command string
 }
 
-func DebugPrintFlags() {
-	for _, f := range flagRegistry {
-		fmt.Printf("-%s --%s - '%s'\n", f.Short, f.Long, reflect.ValueOf(f.ptr).Elem().Interface())
-	}
+// Has reports whether the flag at path was given.
+func (r *ParseResult) Has(path string) bool {
+	_, ok := r.Flags[path]
+	return ok
+}
+
+// String returns the value of the StringValue, PathValue or EnumValue flag at path, or "" if it is not set.
+func (r *ParseResult) String(path string) string {
+	s, _ := r.Flags[path].(string)
+	return s
+}
+
+// Bool returns the value of the BoolValue flag at path, or false if it is not set.
+func (r *ParseResult) Bool(path string) bool {
+	b, _ := r.Flags[path].(bool)
+	return b
+}
+
+// Int returns the value of the IntValue or CountValue flag at path, or 0 if it is not set.
+func (r *ParseResult) Int(path string) int64 {
+	i, _ := r.Flags[path].(int64)
+	return i
+}
+
+// Uint returns the value of the UintValue flag at path, or 0 if it is not set.
+func (r *ParseResult) Uint(path string) uint64 {
+	u, _ := r.Flags[path].(uint64)
+	return u
+}
+
+// Values returns the values of the Repeatable flag at path, e.g. Values[string](r, "from").
+func Values[T any](r *ParseResult, path string) []T {
+	values, _ := r.Flags[path].([]T)
+	return values
+}
+
+// Duration returns the value of the DurationValue flag at path, or 0 if it is not set.
+func (r *ParseResult) Duration(path string) time.Duration {
+	d, _ := r.Flags[path].(time.Duration)
+	return d
+}
+
+func NewParser(name string) *Parser {
+	return &Parser{Name: name, Output: os.Stdout}
 }
 
-func (w *WFlag) setValue(val any) error {
-	p := reflect.ValueOf(w.ptr).Elem()
-	v := reflect.ValueOf(val)
-	switch p.Kind() {
-	case reflect.String:
-		if p.Kind() != v.Kind() {
-			return fmt.Errorf("invalid value type for string flag: %s", v.Kind().String())
+func (p *Parser) AddFlag(flag *WFlag) {
+	checkFlag(flag)
+	p.flags = append(p.flags, flag)
+}
+
+// checkFlag panics if the Ptr or another setting of flag or one of its children
+// does not fit its Type, and links the children to their parent.
+func checkFlag(flag *WFlag) {
+	if flag.Ptr != nil {
+		v := reflect.ValueOf(flag.Ptr)
+		if v.Kind() != reflect.Pointer {
+			panic("flag.Ptr must be a pointer")
 		}
-		p.Set(v)
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		if v.Kind() == reflect.String {
-			i, err := strconv.ParseInt(v.String(), 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid value for int flag: %s", v.String())
+		target := v.Type().Elem()
+		if flag.Repeatable {
+			if target.Kind() != reflect.Slice {
+				panic("flag.Ptr of a repeatable flag must point to a slice")
 			}
-			p.SetInt(i)
-		} else if v.Kind() != p.Kind() {
-			return fmt.Errorf("invalid value type for int flag: %s", v.Kind().String())
-		} else {
-			p.Set(v)
+			target = target.Elem()
 		}
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		if v.Kind() == reflect.String {
-			u, err := strconv.ParseUint(v.String(), 10, 64)
-			if err != nil {
-				return fmt.Errorf("invalid value for uint flag: %s", v.String())
-			}
-			p.SetUint(u)
-		} else if v.Kind() != p.Kind() {
-			return fmt.Errorf("invalid value type for uint flag: %s", v.Kind().String())
-		} else {
-			p.Set(v)
+		t, ok := valueTypeOf(target)
+		if ok && flag.Type == BoolValue {
+			flag.Type = t
+		} else if !ok || !(t == flag.Type ||
+			t == StringValue && (flag.Type == PathValue || flag.Type == EnumValue) ||
+			t == IntValue && flag.Type == CountValue) {
+			panic(fmt.Sprintf("flag.Ptr does not match flag type %s", flag.Type))
 		}
-	case reflect.Bool:
-		if v.Kind() == reflect.String {
-			b, err := strconv.ParseBool(v.String())
-			if err != nil {
-				return fmt.Errorf("invalid value for bool flag: %s", v.String())
-			}
-			p.SetBool(b)
-		} else if v.Kind() != reflect.Bool {
-			return fmt.Errorf("invalid value type for bool flag: %s", v.Kind().String())
-		} else {
-			p.Set(v)
+	}
+	if err := flag.validate(); err != nil {
+		panic(err.Error())
+	}
+	for _, child := range flag.Children {
+		child.Parent = flag
+		checkFlag(child)
+	}
+}
+
+// validate reports settings of the flag that do not fit its Type.
+func (w *WFlag) validate() error {
+	switch {
+	case strings.Contains(w.Short+w.Long, ":"):
+		return fmt.Errorf("flag names cannot contain a colon: -%s --%s", w.Short, w.Long)
+	case w.Type == EnumValue && len(w.Choices) == 0:
+		return fmt.Errorf("enum flag %s has no choices", w.displayName())
+	case w.Type == CountValue && w.Repeatable:
+		return fmt.Errorf("count flag %s cannot be repeatable", w.displayName())
+	case w.NonEmpty && w.Type != StringValue:
+		return fmt.Errorf("only string flags can be non-empty: %s", w.displayName())
+	}
+	return nil
+}
+
+func (p *Parser) AddFlags(flags []*WFlag) {
+	for _, flag := range flags {
+		p.AddFlag(flag)
+	}
+}
+
+func (p *Parser) DebugPrintFlags() {
+	for _, f := range p.flags {
+		if f.Ptr == nil {
+			log.Log(fmt.Sprintf("-%s --%s - unbound", f.Short, f.Long))
+			continue
 		}
-	case reflect.Slice:
-		if v.Kind() != reflect.Slice {
-			return fmt.Errorf("invalid value type for slice flag: %s", v.Kind().String())
+		log.Log(fmt.Sprintf("-%s --%s - '%v'", f.Short, f.Long, reflect.ValueOf(f.Ptr).Elem().Interface()))
+	}
+}
+
+// Name returns the long name of the flag, or the short one if it has none.
+func (w *WFlag) Name() string {
+	if w.Long != "" {
+		return w.Long
+	}
+	return w.Short
+}
+
+// Path returns the dot separated names of the flag and its parents, e.g. "add.short".
+func (w *WFlag) Path() string {
+	if w.Parent == nil {
+		return w.Name()
+	}
+	return w.Parent.Path() + "." + w.Name()
+}
+
+// setValue stores v, as returned by convertValue, in the variable bound by Ptr.
+// Values of a Repeatable flag are appended to the slice.
+func (w *WFlag) setValue(v any) error {
+	if w.Ptr == nil {
+		return nil
+	}
+	p := reflect.ValueOf(w.Ptr).Elem()
+	if !w.Repeatable {
+		return w.setReflectValue(p, v)
+	}
+	elem := reflect.New(p.Type().Elem()).Elem()
+	if err := w.setReflectValue(elem, v); err != nil {
+		return err
+	}
+	p.Set(reflect.Append(p, elem))
+	return nil
+}
+
+func (w *WFlag) setReflectValue(p reflect.Value, v any) error {
+	switch v := v.(type) {
+	case bool:
+		p.SetBool(v)
+	case string:
+		p.SetString(v)
+	case int64:
+		if p.OverflowInt(v) {
+			return fmt.Errorf("value %d out of range for flag %s", v, w.displayName())
 		}
-		if p.Type().Elem() != v.Type().Elem() {
-			return fmt.Errorf("invalid slice element type for flag: %s", v.Type().Elem().String())
+		p.SetInt(v)
+	case uint64:
+		if p.OverflowUint(v) {
+			return fmt.Errorf("value %d out of range for flag %s", v, w.displayName())
 		}
-		p.Set(reflect.AppendSlice(p, v))
+		p.SetUint(v)
+	case time.Duration:
+		p.SetInt(int64(v))
 	default:
-		return fmt.Errorf("unsupported flag type: %s", p.Kind().String())
+		return fmt.Errorf("unsupported value type for flag %s: %T", w.displayName(), v)
 	}
 	return nil
 }
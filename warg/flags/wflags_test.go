@@ -0,0 +1,20 @@
+package flags
+
+import "testing"
+
+func TestCheckFlagInvalid(t *testing.T) {
+	for _, flag := range []*WFlag{
+		{Long: "cr", Type: CountValue, Repeatable: true},
+		{Long: "n", Ptr: new(int), NonEmpty: true},
+		{Long: "add", Children: []*WFlag{{Long: "e", Type: EnumValue}}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("AddFlag(%+v) did not panic", flag)
+				}
+			}()
+			NewParser("test").AddFlag(flag)
+		}()
+	}
+}
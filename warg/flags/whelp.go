@@ -0,0 +1,90 @@
+package flags
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrHelp is returned by Parse after printing the usage for -h/--help.
+var ErrHelp = errors.New("flags: help requested")
+
+// Usage writes the usage line and the help of every flag, children indented under their parent.
+// Subcommands are listed with their Help.
+func (p *Parser) Usage() {
+	out := p.output()
+	usage := "Usage: " + p.fullName() + " [flags]"
+	if len(p.commands) > 0 {
+		usage += " <command> ..."
+	}
+	if p.Help != "" {
+		usage += "\n\n" + p.Help
+	}
+	fmt.Fprintf(out, "%s\n\nFlags:\n", usage)
+	rows := flagHelpRows(p.flags, 1)
+	rows = append(rows, [2]string{"  -h, --help", "show this help"})
+	writeRows(out, rows)
+
+	if len(p.commands) > 0 {
+		fmt.Fprintf(out, "\nCommands:\n")
+		rows = nil
+		for _, cmd := range p.commands {
+			rows = append(rows, [2]string{"  " + cmd.Name, cmd.Help})
+		}
+		writeRows(out, rows)
+	}
+}
+
+// writeRows writes two aligned columns, leaving no trailing space when the second one is empty.
+func writeRows(out io.Writer, rows [][2]string) {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row[0]))
+	}
+	for _, row := range rows {
+		if row[1] == "" {
+			fmt.Fprintln(out, row[0])
+			continue
+		}
+		fmt.Fprintf(out, "%-*s  %s\n", width, row[0], row[1])
+	}
+}
+
+func flagHelpRows(flags []*WFlag, depth int) [][2]string {
+	var rows [][2]string
+	for _, f := range flags {
+		help := f.Help
+		if f.Type == EnumValue {
+			help += fmt.Sprintf(" (one of: %s)", strings.Join(f.Choices, ", "))
+		}
+		if f.Required {
+			help += " (required)"
+		}
+		if f.Default != "" {
+			help += fmt.Sprintf(" (default: %s)", f.Default)
+		}
+		rows = append(rows, [2]string{strings.Repeat("  ", depth) + flagUsage(f), strings.TrimSpace(help)})
+		rows = append(rows, flagHelpRows(f.Children, depth+1)...)
+	}
+	return rows
+}
+
+func flagUsage(f *WFlag) string {
+	var usage string
+	switch {
+	case f.Short == "":
+		usage = "    --" + f.Long
+	case f.Long == "":
+		usage = "-" + f.Short
+	default:
+		usage = "-" + f.Short + ", --" + f.Long
+	}
+	if f.takesValue() {
+		usage += " <" + f.Type.String() + ">"
+	}
+	if f.Repeatable || f.Type == CountValue {
+		usage += "..."
+	}
+	return usage
+}
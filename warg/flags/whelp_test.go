@@ -0,0 +1,72 @@
+package flags
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestUsage(t *testing.T) {
+	var out bytes.Buffer
+	p := NewParser("tool")
+	p.Output = &out
+	p.AddFlags([]*WFlag{
+		{Short: "v", Long: "verbose", Help: "more output", Type: CountValue},
+		{Long: "dry-run"},
+		{Short: "o", Long: "output", Help: "output format", Type: EnumValue, Choices: []string{"text", "json"}, Default: "text"},
+		{Short: "r", Long: "remote", Help: "remote settings", Children: []*WFlag{
+			{Short: "u", Long: "url", Help: "remote URL", Type: StringValue, Required: true},
+			{Short: "t", Long: "timeout", Help: "request timeout", Type: DurationValue, Default: "30s"},
+			{Short: "a", Long: "auth", Children: []*WFlag{
+				{Short: "k", Help: "key files", Type: PathValue, Repeatable: true},
+			}},
+		}},
+	})
+	p.AddCommand("add", "add a file")
+	p.AddCommand("status", "")
+	p.Usage()
+
+	want := `Usage: tool [flags] <command> ...
+
+Flags:
+  -v, --verbose...            more output
+      --dry-run
+  -o, --output <enum>         output format (one of: text, json) (default: text)
+  -r, --remote                remote settings
+    -u, --url <string>        remote URL (required)
+    -t, --timeout <duration>  request timeout (default: 30s)
+    -a, --auth
+      -k <path>...            key files
+  -h, --help                  show this help
+
+Commands:
+  add     add a file
+  status
+`
+	if out.String() != want {
+		t.Errorf("Usage() wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestCommandUsage(t *testing.T) {
+	var out bytes.Buffer
+	p := NewParser("tool")
+	p.Output = &out
+	add := p.AddCommand("add", "add a file")
+	add.AddFlag(&WFlag{Short: "f", Long: "force", Help: "overwrite"})
+
+	if _, err := p.Parse([]string{"add", "--help"}); !errors.Is(err, ErrHelp) {
+		t.Fatalf("Parse error = %v, want ErrHelp", err)
+	}
+	want := `Usage: tool add [flags]
+
+add a file
+
+Flags:
+  -f, --force  overwrite
+  -h, --help   show this help
+`
+	if out.String() != want {
+		t.Errorf("Usage() wrote\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,110 @@
+package flags
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JSON returns the result as {"flags": {...}, "args": [...], "command": "..."}.
+// Durations are written as strings such as "1h30m0s".
+func (r *ParseResult) JSON() ([]byte, error) {
+	out := struct {
+		Flags   map[string]any `json:"flags"`
+		Args    []string       `json:"args"`
+		Command string         `json:"command,omitempty"`
+	}{map[string]any{}, r.Args, r.Command}
+	for path, v := range r.Flags {
+		switch d := v.(type) {
+		case time.Duration:
+			v = d.String()
+		case []time.Duration:
+			durations := make([]string, len(d))
+			for i := range d {
+				durations[i] = d[i].String()
+			}
+			v = durations
+		}
+		out.Flags[path] = v
+	}
+	if out.Args == nil {
+		out.Args = []string{}
+	}
+	return json.Marshal(out)
+}
+
+// Env returns the result as shell code for eval. Every flag becomes a
+// variable named prefix followed by its path with non-alphanumeric
+// characters replaced by underscores (add.short -> add_short), the command
+// is stored in prefix followed by COMMAND and the positional arguments are
+// set with "set --". A repeatable flag becomes indexed variables plus a
+// count: from_0, from_1, from_COUNT. Env fails if two variables get the
+// same name, so a prefix such as "WARG_" also keeps flags from clobbering
+// shell variables like PATH or IFS.
+func (r *ParseResult) Env(prefix string) (string, error) {
+	if prefix != "" && envName(prefix) != prefix {
+		return "", fmt.Errorf("invalid shell variable prefix: %q", prefix)
+	}
+	var b strings.Builder
+	// sources maps each variable to the flag or value it was set from.
+	sources := map[string]string{}
+	assign := func(name, source, value string) error {
+		if other, ok := sources[name]; ok {
+			return fmt.Errorf("%s and %s both map to the shell variable %s", other, source, name)
+		}
+		sources[name] = source
+		fmt.Fprintf(&b, "%s=%s\n", name, value)
+		return nil
+	}
+	if r.Command != "" {
+		if err := assign(prefix+"COMMAND", "the command", shellQuote(r.Command)); err != nil {
+			return "", err
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(r.Flags)) {
+		name := prefix + envName(path)
+		values := reflect.ValueOf(r.Flags[path])
+		if values.Kind() != reflect.Slice {
+			if err := assign(name, path, shellQuote(fmt.Sprint(r.Flags[path]))); err != nil {
+				return "", err
+			}
+			continue
+		}
+		for i := range values.Len() {
+			if err := assign(fmt.Sprintf("%s_%d", name, i), path, shellQuote(fmt.Sprint(values.Index(i).Interface()))); err != nil {
+				return "", err
+			}
+		}
+		if err := assign(name+"_COUNT", path, strconv.Itoa(values.Len())); err != nil {
+			return "", err
+		}
+	}
+	b.WriteString("set --")
+	for _, arg := range r.Args {
+		b.WriteString(" " + shellQuote(arg))
+	}
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+func envName(path string) string {
+	name := []rune(path)
+	for i, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			name[i] = '_'
+		}
+	}
+	if len(name) > 0 && name[0] >= '0' && name[0] <= '9' {
+		return "_" + string(name)
+	}
+	return string(name)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,44 @@
+package flags
+
+import "testing"
+
+func TestEnv(t *testing.T) {
+	r := &ParseResult{
+		Flags:   map[string]any{"path": "/x", "add.short": "it's", "from": []string{"a", "b"}},
+		Args:    []string{"a b"},
+		Command: "remote add",
+	}
+	want := `WARG_COMMAND='remote add'
+WARG_add_short='it'\''s'
+WARG_from_0='a'
+WARG_from_1='b'
+WARG_from_COUNT=2
+WARG_path='/x'
+set -- 'a b'
+`
+	got, err := r.Env("WARG_")
+	if err != nil {
+		t.Fatalf("Env failed: %v", err)
+	}
+	if got != want {
+		t.Errorf("Env = %q, want %q", got, want)
+	}
+}
+
+func TestEnvErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		flags  map[string]any
+		prefix string
+	}{
+		{"same name", map[string]any{"add.short": "a", "add_short": "b"}, "WARG_"},
+		{"repeatable index", map[string]any{"from": []string{"a"}, "from_0": "b"}, "WARG_"},
+		{"repeatable count", map[string]any{"from": []string{"a"}, "from.COUNT": "b"}, ""},
+		{"invalid prefix", map[string]any{"path": "/x"}, "a-b"},
+	}
+	for _, tt := range tests {
+		if env, err := (&ParseResult{Flags: tt.flags}).Env(tt.prefix); err == nil {
+			t.Errorf("%s: Env = %q, want an error", tt.name, env)
+		}
+	}
+}
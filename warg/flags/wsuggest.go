@@ -0,0 +1,69 @@
+package flags
+
+import (
+	"slices"
+	"strings"
+)
+
+// suggest returns up to three of names that are close to the unknown flag name,
+// closest first. Long names are compared by edit distance, short ones only by case.
+func suggest(name string, names []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	var candidates []candidate
+	long, isLong := strings.CutPrefix(name, "--")
+	for _, n := range names {
+		other, otherLong := strings.CutPrefix(n, "--")
+		if isLong != otherLong {
+			continue
+		}
+		if !isLong {
+			if strings.EqualFold(n, name) {
+				candidates = append(candidates, candidate{n, 1})
+			}
+			continue
+		}
+		if d := editDistance(long, other); d <= max(1, len([]rune(long))/3) {
+			candidates = append(candidates, candidate{n, d})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return a.distance - b.distance
+	})
+	var suggestions []string
+	for _, c := range candidates[:min(3, len(candidates))] {
+		suggestions = append(suggestions, c.name)
+	}
+	return suggestions
+}
+
+// editDistance returns the optimal string alignment distance between a and b,
+// the number of single rune insertions, deletions, substitutions and
+// transpositions of adjacent runes turning a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between the first i runes of a and the first j of b.
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,49 @@
+package flags
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"xray", "xray", 0},
+		{"", "abc", 3},
+		{"xray", "xrya", 1},
+		{"xray", "rxay", 1},
+		{"xray", "xay", 1},
+		{"xray", "xraay", 1},
+		{"xray", "xrby", 1},
+		{"ca", "abc", 3},
+		{"verbose", "vrebsoe", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	names := []string{"--xray", "--verbose", "--version", "-v", "-x"}
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"--xrya", []string{"--xray"}},
+		{"--verbsoe", []string{"--verbose"}},
+		{"--verson", []string{"--version"}},
+		{"--zzzz", nil},
+		{"-V", []string{"-v"}},
+		{"-q", nil},
+	}
+	for _, tt := range tests {
+		if got := suggest(tt.name, names); !slices.Equal(got, tt.want) {
+			t.Errorf("suggest(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package flags
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits a command line into arguments. Whitespace separates
+// arguments, single quotes keep their content literally, double quotes keep
+// it except for \" \\ \$ and \` escapes, and a backslash outside quotes
+// escapes the next character.
+func Tokenize(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 == len(runes) {
+				return nil, newParseError(ErrSyntax, line, "trailing backslash in %q", line)
+			}
+			i++
+			cur.WriteRune(runes[i])
+			inArg = true
+		case r == '\'':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\'' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, newParseError(ErrSyntax, line, "unterminated single quote in %q", line)
+			}
+			cur.WriteString(string(runes[i+1 : end]))
+			i = end
+			inArg = true
+		case r == '"':
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && strings.ContainsRune("\"\\$`", runes[i+1]) {
+					i++
+				}
+				cur.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return nil, newParseError(ErrSyntax, line, "unterminated double quote in %q", line)
+			}
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,53 @@
+package flags
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  -a   b\tc  ", []string{"-a", "b", "c"}},
+		{`'a b' c`, []string{"a b", "c"}},
+		{`'a\b "c" $d'`, []string{`a\b "c" $d`}},
+		{`"a b" c`, []string{"a b", "c"}},
+		{`"\" \\ \$ \` + "`" + `"`, []string{`" \ $ ` + "`"}},
+		{`"a\b \n"`, []string{`a\b \n`}},
+		{`"it's"`, []string{"it's"}},
+		{`a\ b \'c`, []string{"a b", "'c"}},
+		{`--msg="hello world"`, []string{"--msg=hello world"}},
+		{`a"b"'c'd`, []string{"abcd"}},
+		{`"" ''`, []string{"", ""}},
+		{`-s ""`, []string{"-s", ""}},
+	}
+	for _, tt := range tests {
+		got, err := Tokenize(tt.line)
+		if err != nil {
+			t.Errorf("Tokenize(%q) failed: %v", tt.line, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	for _, line := range []string{
+		`'abc`,
+		`a 'b c`,
+		`"abc`,
+		`"abc\"`,
+		`abc\`,
+		`"a" \`,
+	} {
+		if args, err := Tokenize(line); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Tokenize(%q) = %q, %v, want a syntax error", line, args, err)
+		}
+	}
+}
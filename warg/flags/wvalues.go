@@ -0,0 +1,162 @@
+package flags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValueType is the type of value a flag takes. BoolValue and CountValue flags take no value
+// on the command line, they are true when given or count how often they are given.
+type ValueType int
+
+const (
+	BoolValue ValueType = iota
+	StringValue
+	IntValue
+	UintValue
+	// DurationValue takes a time.ParseDuration string such as "1h30m".
+	DurationValue
+	// PathValue takes a file path, cleaned and with a leading ~ expanded to the home directory.
+	PathValue
+	// EnumValue takes one of the flag's Choices.
+	EnumValue
+	// CountValue counts the occurrences of the flag, so -vvv gives 3.
+	CountValue
+)
+
+var valueTypeNames = map[ValueType]string{
+	BoolValue:     "bool",
+	StringValue:   "string",
+	IntValue:      "int",
+	UintValue:     "uint",
+	DurationValue: "duration",
+	PathValue:     "path",
+	EnumValue:     "enum",
+	CountValue:    "count",
+}
+
+func (t ValueType) String() string {
+	if name, ok := valueTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ValueType(%d)", int(t))
+}
+
+func ParseValueType(s string) (ValueType, error) {
+	for t, name := range valueTypeNames {
+		if name == s {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown value type: %s", s)
+}
+
+func (t ValueType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *ValueType) UnmarshalText(text []byte) error {
+	parsed, err := ParseValueType(string(text))
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// valueTypeOf returns the ValueType matching the Go type t.
+func valueTypeOf(t reflect.Type) (ValueType, bool) {
+	switch t.Kind() {
+	case reflect.Bool:
+		return BoolValue, true
+	case reflect.String:
+		return StringValue, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t == reflect.TypeFor[time.Duration]() {
+			return DurationValue, true
+		}
+		return IntValue, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return UintValue, true
+	}
+	return 0, false
+}
+
+// convertValue validates s against the flag's type and returns it as a bool,
+// string, int64, uint64 or time.Duration.
+func (w *WFlag) convertValue(s string) (any, error) {
+	switch w.Type {
+	case BoolValue:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, fmt.Errorf("flag %s expects a boolean, got %q", w.displayName(), s)
+		}
+		return b, nil
+	case StringValue:
+		if w.NonEmpty && s == "" {
+			return nil, fmt.Errorf("flag %s requires a non-empty value", w.displayName())
+		}
+		return s, nil
+	case IntValue:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("flag %s expects an integer, got %q", w.displayName(), s)
+		}
+		return i, nil
+	case UintValue:
+		u, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("flag %s expects an unsigned integer, got %q", w.displayName(), s)
+		}
+		return u, nil
+	case DurationValue:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("flag %s expects a duration such as 90s or 1h30m, got %q", w.displayName(), s)
+		}
+		return d, nil
+	case PathValue:
+		if s == "" {
+			return nil, fmt.Errorf("flag %s expects a path", w.displayName())
+		}
+		if s == "~" || strings.HasPrefix(s, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("flag %s: %w", w.displayName(), err)
+			}
+			s = home + s[1:]
+		}
+		return filepath.Clean(s), nil
+	case CountValue:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("flag %s expects a count, got %q", w.displayName(), s)
+		}
+		return n, nil
+	case EnumValue:
+		if !slices.Contains(w.Choices, s) {
+			return nil, fmt.Errorf("flag %s expects one of %s, got %q", w.displayName(), strings.Join(w.Choices, ", "), s)
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("flag %s has unknown type %s", w.displayName(), w.Type)
+}
+
+// takesValue reports whether the flag needs a value on the command line.
+func (w *WFlag) takesValue() bool {
+	return w.Type != BoolValue && w.Type != CountValue
+}
+
+// displayName returns the flag as it is written on the command line, e.g. "--short" or "-s".
+func (w *WFlag) displayName() string {
+	if w.Long != "" {
+		return "--" + w.Long
+	}
+	return "-" + w.Short
+}
@@ -0,0 +1,113 @@
+package flags
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestConvertValue(t *testing.T) {
+	t.Setenv("HOME", "/home/u")
+	enum := &WFlag{Long: "mode", Type: EnumValue, Choices: []string{"push", "fetch"}}
+	tests := []struct {
+		flag  *WFlag
+		value string
+		want  any
+	}{
+		{&WFlag{Long: "b"}, "false", false},
+		{&WFlag{Long: "s", Type: StringValue}, "", ""},
+		{&WFlag{Long: "i", Type: IntValue}, "-7", int64(-7)},
+		{&WFlag{Long: "u", Type: UintValue}, "7", uint64(7)},
+		{&WFlag{Long: "c", Type: CountValue}, "3", int64(3)},
+		{&WFlag{Long: "d", Type: DurationValue}, "90s", 90 * time.Second},
+		{&WFlag{Long: "d", Type: DurationValue}, "1h30m", 90 * time.Minute},
+		{&WFlag{Long: "d", Type: DurationValue}, "0", time.Duration(0)},
+		{&WFlag{Long: "p", Type: PathValue}, "~", "/home/u"},
+		{&WFlag{Long: "p", Type: PathValue}, "~/a//b/", "/home/u/a/b"},
+		{&WFlag{Long: "p", Type: PathValue}, "~user/a", "~user/a"},
+		{&WFlag{Long: "p", Type: PathValue}, "a/./b/../c", "a/c"},
+		{&WFlag{Long: "p", Type: PathValue}, "/x/", "/x"},
+		{enum, "fetch", "fetch"},
+	}
+	for _, tt := range tests {
+		got, err := tt.flag.convertValue(tt.value)
+		if err != nil {
+			t.Errorf("convertValue(%q) for %s %s failed: %v", tt.value, tt.flag.Type, tt.flag.displayName(), err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("convertValue(%q) for %s %s = %#v, want %#v", tt.value, tt.flag.Type, tt.flag.displayName(), got, tt.want)
+		}
+	}
+}
+
+func TestConvertValueInvalid(t *testing.T) {
+	enum := &WFlag{Long: "mode", Type: EnumValue, Choices: []string{"push", "fetch"}}
+	tests := []struct {
+		flag  *WFlag
+		value string
+	}{
+		{&WFlag{Long: "b"}, "yes"},
+		{&WFlag{Long: "s", Type: StringValue, NonEmpty: true}, ""},
+		{&WFlag{Long: "i", Type: IntValue}, "1.5"},
+		{&WFlag{Long: "u", Type: UintValue}, "-1"},
+		{&WFlag{Long: "c", Type: CountValue}, "-1"},
+		{&WFlag{Long: "d", Type: DurationValue}, "90"},
+		{&WFlag{Long: "d", Type: DurationValue}, "soon"},
+		{&WFlag{Long: "p", Type: PathValue}, ""},
+		{enum, "Fetch"},
+		{enum, "pull"},
+		{enum, ""},
+	}
+	for _, tt := range tests {
+		if got, err := tt.flag.convertValue(tt.value); err == nil {
+			t.Errorf("convertValue(%q) for %s %s = %#v, want an error", tt.value, tt.flag.Type, tt.flag.displayName(), got)
+		}
+	}
+}
+
+func TestParsePtr(t *testing.T) {
+	var (
+		timeout time.Duration
+		dir     string
+		mode    string
+		small   int8
+	)
+	p := NewParser("test")
+	p.Output = io.Discard
+	p.AddFlags([]*WFlag{
+		{Long: "timeout", Ptr: &timeout},
+		{Long: "dir", Type: PathValue, Ptr: &dir},
+		{Long: "mode", Type: EnumValue, Choices: []string{"push", "fetch"}, Default: "fetch", Ptr: &mode},
+		{Long: "small", Ptr: &small},
+	})
+	if p.Lookup("timeout").Type != DurationValue {
+		t.Errorf("type of a *time.Duration flag = %s, want duration", p.Lookup("timeout").Type)
+	}
+	if _, err := p.Parse([]string{"--timeout", "1m30s", "--dir", "a/../b", "--small", "-8"}); err != nil {
+		t.Fatal(err)
+	}
+	if timeout != 90*time.Second || dir != "b" || mode != "fetch" || small != -8 {
+		t.Errorf("Parse set %v %q %q %d, want 1m30s \"b\" \"fetch\" -8", timeout, dir, mode, small)
+	}
+	if _, err := p.Parse([]string{"--small", "200"}); err == nil {
+		t.Errorf("Parse of an out of range int8 succeeded, want an error")
+	}
+}
+
+func TestValueTypeText(t *testing.T) {
+	for typ, name := range valueTypeNames {
+		text, err := typ.MarshalText()
+		if err != nil || string(text) != name {
+			t.Errorf("%d.MarshalText() = %q, %v, want %q", typ, text, err, name)
+		}
+		var parsed ValueType
+		if err := parsed.UnmarshalText(text); err != nil || parsed != typ {
+			t.Errorf("UnmarshalText(%q) = %s, %v, want %s", text, parsed, err, typ)
+		}
+	}
+	var parsed ValueType
+	if err := parsed.UnmarshalText([]byte("float")); err == nil {
+		t.Errorf("UnmarshalText(float) succeeded, want an error")
+	}
+}
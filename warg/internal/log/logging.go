@@ -1,25 +1,74 @@
 package log
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
 
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[string]Level{
+	"debug":   DebugLevel,
+	"info":    InfoLevel,
+	"warn":    WarnLevel,
+	"warning": WarnLevel,
+	"error":   ErrorLevel,
+}
+
+// level is read from WARG_LOG_LEVEL (debug, info, warn or error), InfoLevel by default.
+var level = levelFromEnv()
+
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+func levelFromEnv() Level {
+	if l, ok := levelNames[strings.ToLower(os.Getenv("WARG_LOG_LEVEL"))]; ok {
+		return l
+	}
+	return InfoLevel
+}
+
+func SetLevel(l Level) {
+	level = l
+}
+
+// Log writes s to stdout regardless of the level.
 func Log(s string) {
-	fmt.Println(s)
+	fmt.Fprintln(stdout, s)
 }
 
+// Stdout writes s to stdout at InfoLevel.
 func Stdout(s string) {
-
+	if level <= InfoLevel {
+		fmt.Fprintln(stdout, s)
+	}
 }
 
 func Debug(s string) {
-
+	if level <= DebugLevel {
+		fmt.Fprintln(stderr, "debug: "+s)
+	}
 }
 
 func Warn(s string) {
-
+	if level <= WarnLevel {
+		fmt.Fprintln(stderr, "warning: "+s)
+	}
 }
 
+// Error writes s to stderr regardless of the level. It is meant for commands,
+// the flags package returns its errors to the caller instead of logging them.
 func Error(s string) {
-	Log(s)
+	fmt.Fprintln(stderr, "error: "+s)
 }
-
-